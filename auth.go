@@ -0,0 +1,263 @@
+package main
+
+// Authentication and per-page access control: users.json holds
+// bcrypt-hashed passwords, a signed session cookie (HMAC-SHA256 over
+// userid|expiry) identifies a logged-in user, and an optional .acl
+// sidecar per page restricts who may read or write it.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	usersPath         = "users.json"
+	sessionCookieName = "session"
+	sessionTTL        = 24 * time.Hour
+
+	// minSessionSecretLen is the shortest WIKI_SESSION_SECRET main will
+	// accept. An empty or trivially short secret makes session cookies
+	// forgeable, so we fail closed instead of signing with a weak key.
+	minSessionSecretLen = 32
+)
+
+// users maps username to bcrypt password hash, loaded once in main.
+var users map[string]string
+
+// sessionSecretKey is the server-side HMAC key, loaded once by
+// loadSessionSecret in main so it isn't re-read from the environment on
+// every sign/verify call.
+var sessionSecretKey []byte
+
+// loadSessionSecret reads WIKI_SESSION_SECRET and fails closed if it's
+// unset or too short, rather than silently signing sessions with a key
+// an attacker could guess (the empty string).
+func loadSessionSecret() {
+	secret := os.Getenv("WIKI_SESSION_SECRET")
+	if len(secret) < minSessionSecretLen {
+		log.Fatalf("WIKI_SESSION_SECRET must be set to at least %d bytes", minSessionSecretLen)
+	}
+	sessionSecretKey = []byte(secret)
+}
+
+type userRecord struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// loadUsers reads users.json into a username -> bcrypt hash map.
+func loadUsers(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []userRecord
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(records))
+	for _, u := range records {
+		m[u.Username] = u.PasswordHash
+	}
+	return m, nil
+}
+
+// authenticate reports whether password matches username's stored hash.
+func authenticate(users map[string]string, username, password string) bool {
+	hash, ok := users[username]
+	if !ok {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// signSession issues a session token for username, good for sessionTTL.
+func signSession(username string) string {
+	payload := username + "|" + strconv.FormatInt(time.Now().Add(sessionTTL).Unix(), 10)
+	return encodeSession([]byte(payload))
+}
+
+func encodeSession(payload []byte) string {
+	mac := hmac.New(sha256.New, sessionSecretKey)
+	mac.Write(payload)
+	return base64.URLEncoding.EncodeToString(payload) + "." + base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySession checks a session token's signature and expiry, returning
+// the username it was issued for.
+func verifySession(token string) (string, bool) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	payload, err := base64.URLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	sig, err := base64.URLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, sessionSecretKey)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return "", false
+	}
+
+	fields := strings.SplitN(string(payload), "|", 2)
+	if len(fields) != 2 {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return "", false
+	}
+	return fields[0], true
+}
+
+// currentUser extracts the authenticated username from the session
+// cookie, reporting ok=false if there is none or it's invalid/expired.
+func currentUser(r *http.Request) (string, bool) {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", false
+	}
+	return verifySession(c.Value)
+}
+
+// csrfToken derives a per-user CSRF token from the session secret, so
+// saveHandler can validate it without server-side session storage.
+func csrfToken(username string) string {
+	mac := hmac.New(sha256.New, sessionSecretKey)
+	mac.Write([]byte("csrf|" + username))
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// loginHandler renders the login form and, on POST, authenticates the
+// user and issues a session cookie.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		data := struct{ Next string }{Next: r.URL.Query().Get("next")}
+		if err := templates.ExecuteTemplate(w, "login.html", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	username := r.FormValue("username")
+	if !authenticate(users, username, r.FormValue("password")) {
+		http.Error(w, "invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    signSession(username),
+		Path:     "/",
+		HttpOnly: true,
+		Expires:  time.Now().Add(sessionTTL),
+	})
+
+	next := r.FormValue("next")
+	if next == "" {
+		next = "/view/index"
+	}
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+// logoutHandler clears the session cookie.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+	http.Redirect(w, r, "/view/index", http.StatusFound)
+}
+
+// makeAuthHandler wraps fn the way makeHandler does, but first requires
+// a valid session, redirecting to /login when there is none.
+func makeAuthHandler(fn func(http.ResponseWriter, *http.Request, string, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m := validPath.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			http.NotFound(w, r)
+			return
+		}
+		username, ok := currentUser(r)
+		if !ok {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.Path), http.StatusFound)
+			return
+		}
+		fn(w, r, m[2], username)
+	}
+}
+
+// acl is a page's optional .acl sidecar: a missing entry means
+// world-readable, authenticated-writable; an empty list for a present
+// entry means "anyone" for read, "any authenticated user" for write.
+type acl struct {
+	Read  []string `json:"read"`
+	Write []string `json:"write"`
+}
+
+func loadACL(title string) (*acl, error) {
+	b, err := store.GetACL(title)
+	if err != nil {
+		return nil, err
+	}
+	var a acl
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// canRead reports whether username (empty if anonymous) may view title.
+// Only a missing .acl is treated as "no ACL configured"; any other
+// error (unreadable sidecar, corrupt JSON) fails closed and denies.
+func canRead(title, username string) bool {
+	a, err := loadACL(title)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return aclAllows(a.Read, username, true)
+}
+
+// canWrite reports whether username may edit/save/restore title. Only
+// a missing .acl is treated as "no ACL configured"; any other error
+// (unreadable sidecar, corrupt JSON) fails closed and denies.
+func canWrite(title, username string) bool {
+	a, err := loadACL(title)
+	if err != nil {
+		return os.IsNotExist(err) && username != ""
+	}
+	return aclAllows(a.Write, username, username != "")
+}
+
+// aclAllows reports whether username is in list, or def if list is empty.
+func aclAllows(list []string, username string, def bool) bool {
+	if len(list) == 0 {
+		return def
+	}
+	for _, u := range list {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
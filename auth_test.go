@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init() {
+	// signSession/verifySession/encodeSession all need a key; tests
+	// don't go through loadSessionSecret's environment/length checks.
+	sessionSecretKey = []byte("test-secret-key-not-for-production")
+}
+
+func TestVerifySessionRoundTrip(t *testing.T) {
+	token := signSession("alice")
+	got, ok := verifySession(token)
+	if !ok || got != "alice" {
+		t.Fatalf("verifySession(%q) = %q, %v; want \"alice\", true", token, got, ok)
+	}
+}
+
+func TestVerifySessionTampered(t *testing.T) {
+	token := signSession("alice")
+	tampered := token[:len(token)-1] + "x"
+	if _, ok := verifySession(tampered); ok {
+		t.Fatal("verifySession accepted a token with a flipped signature byte")
+	}
+}
+
+func TestVerifySessionWrongKey(t *testing.T) {
+	token := signSession("alice")
+	saved := sessionSecretKey
+	sessionSecretKey = []byte("a different secret key entirely")
+	defer func() { sessionSecretKey = saved }()
+
+	if _, ok := verifySession(token); ok {
+		t.Fatal("verifySession accepted a token signed with a different key")
+	}
+}
+
+func TestVerifySessionExpired(t *testing.T) {
+	payload := "alice|" + strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	token := encodeSession([]byte(payload))
+	if _, ok := verifySession(token); ok {
+		t.Fatal("verifySession accepted an expired token")
+	}
+}
+
+func TestVerifySessionMalformed(t *testing.T) {
+	for _, token := range []string{"", "no-dot-here", "not-base64!.also-not-base64!"} {
+		if _, ok := verifySession(token); ok {
+			t.Errorf("verifySession(%q): want false, got true", token)
+		}
+	}
+}
+
+// fakeACLStore is a minimal RevisionStore that only GetACL cares about,
+// used to drive canRead/canWrite through the not-exist vs. other-error
+// paths that the real backends can hit (missing .acl, corrupt .acl,
+// a read error).
+type fakeACLStore struct {
+	acl []byte
+	err error
+}
+
+func (fakeACLStore) Get(string) ([]byte, error)                 { return nil, os.ErrNotExist }
+func (fakeACLStore) Put(string, []byte) error                   { return nil }
+func (fakeACLStore) List() ([]string, error)                    { return nil, nil }
+func (fakeACLStore) Delete(string) error                        { return nil }
+func (fakeACLStore) GetRevision(string, string) ([]byte, error) { return nil, os.ErrNotExist }
+func (fakeACLStore) ListRevisions(string) ([]string, error)     { return nil, nil }
+func (s fakeACLStore) GetACL(string) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.acl, nil
+}
+
+func withStore(s RevisionStore, fn func()) {
+	saved := store
+	store = s
+	defer func() { store = saved }()
+	fn()
+}
+
+func TestCanReadNoACLDefaultsOpen(t *testing.T) {
+	withStore(fakeACLStore{err: os.ErrNotExist}, func() {
+		if !canRead("Home", "") {
+			t.Error("canRead with no .acl: want true (default open)")
+		}
+	})
+}
+
+func TestCanWriteNoACLRequiresLogin(t *testing.T) {
+	withStore(fakeACLStore{err: os.ErrNotExist}, func() {
+		if canWrite("Home", "") {
+			t.Error("canWrite with no .acl and no user: want false")
+		}
+		if !canWrite("Home", "alice") {
+			t.Error("canWrite with no .acl and a logged-in user: want true")
+		}
+	})
+}
+
+func TestCanReadCorruptACLFailsClosed(t *testing.T) {
+	withStore(fakeACLStore{acl: []byte("not json")}, func() {
+		if canRead("Home", "alice") {
+			t.Error("canRead with corrupt .acl: want false (fail closed)")
+		}
+	})
+}
+
+func TestCanWriteCorruptACLFailsClosed(t *testing.T) {
+	withStore(fakeACLStore{acl: []byte("not json")}, func() {
+		if canWrite("Home", "alice") {
+			t.Error("canWrite with corrupt .acl: want false (fail closed)")
+		}
+	})
+}
+
+func TestCanReadOtherErrorFailsClosed(t *testing.T) {
+	withStore(fakeACLStore{err: errors.New("disk error")}, func() {
+		if canRead("Home", "alice") {
+			t.Error("canRead with a non-not-exist GetACL error: want false (fail closed)")
+		}
+	})
+}
+
+func TestCanReadWriteRespectACLLists(t *testing.T) {
+	withStore(fakeACLStore{acl: []byte(`{"read":["alice"],"write":["alice"]}`)}, func() {
+		if !canRead("Home", "alice") {
+			t.Error("canRead: alice is in the read list, want true")
+		}
+		if canRead("Home", "bob") {
+			t.Error("canRead: bob is not in the read list, want false")
+		}
+		if !canWrite("Home", "alice") {
+			t.Error("canWrite: alice is in the write list, want true")
+		}
+		if canWrite("Home", "bob") {
+			t.Error("canWrite: bob is not in the write list, want false")
+		}
+	})
+}
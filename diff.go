@@ -0,0 +1,167 @@
+package main
+
+// Line-based unified diff between two page revisions, used by the
+// /diff/<title>?a=<id>&b=<id> handler.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is a single line-level edit produced by lcsDiff: kept (' '),
+// removed ('-') from a, or added ('+') from b.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// maxLCSLines caps the revision sizes lcsDiff will run its O(n*m)
+// dynamic-programming backtrace on. /diff/<title>?a=&b= is reachable
+// without write access, so two large revisions (e.g. a page someone
+// pasted a big log into twice) must not be able to force an arbitrarily
+// large time/memory allocation; beyond the cap, replaceDiff is used
+// instead.
+const maxLCSLines = 3000
+
+// lcsDiff returns the edit script turning a into b, found via the
+// standard dynamic-programming longest-common-subsequence backtrace.
+// For revisions larger than maxLCSLines it falls back to replaceDiff,
+// which is linear but doesn't find a minimal edit script.
+func lcsDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n > maxLCSLines || m > maxLCSLines {
+		return replaceDiff(a, b)
+	}
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				dp[i][j] = dp[i+1][j+1] + 1
+			case dp[i+1][j] >= dp[i][j+1]:
+				dp[i][j] = dp[i+1][j]
+			default:
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// replaceDiff is lcsDiff's linear-time fallback for oversized revisions:
+// it reports every line of a removed and every line of b added, with no
+// attempt to find lines common to both. Correct but not minimal.
+func replaceDiff(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// unifiedDiff renders a diff -u style unified diff between two revision
+// bodies, grouping changed lines into hunks with a few lines of context.
+func unifiedDiff(aLabel, bLabel string, a, b []byte) string {
+	const context = 3
+	ops := lcsDiff(splitLines(a), splitLines(b))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n", aLabel)
+	fmt.Fprintf(&buf, "+++ %s\n", bLabel)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		for start > 0 && i-start < context && ops[start-1].kind == ' ' {
+			start--
+		}
+		end := i
+		for end < len(ops) && ops[end].kind != ' ' {
+			end++
+		}
+		stop := end
+		for stop < len(ops) && stop-end < context && ops[stop].kind == ' ' {
+			stop++
+		}
+
+		aStart, bStart := lineNumbers(ops[:start])
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:stop] {
+			switch op.kind {
+			case ' ':
+				aCount++
+				bCount++
+			case '-':
+				aCount++
+			case '+':
+				bCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart+1, aCount, bStart+1, bCount)
+		for _, op := range ops[start:stop] {
+			fmt.Fprintf(&buf, "%c%s\n", op.kind, op.text)
+		}
+		i = stop
+	}
+	return buf.String()
+}
+
+// lineNumbers returns how many a/b lines the given ops prefix consumes,
+// i.e. the 0-based a/b line index the next op starts at.
+func lineNumbers(ops []diffOp) (aLine, bLine int) {
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			aLine++
+			bLine++
+		case '-':
+			aLine++
+		case '+':
+			bLine++
+		}
+	}
+	return aLine, bLine
+}
+
+// splitLines splits a revision body into lines, dropping a single
+// trailing newline so files ending in "\n" don't report a phantom blank line.
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
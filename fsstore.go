@@ -0,0 +1,145 @@
+package main
+
+// FSStore is the original file-based persistence, restated as a
+// RevisionStore: every Put writes a new, timestamped revision file
+// under <root>/<title>/ and repoints a HEAD pointer file at it, so the
+// current view path stays O(1). A per-title mutex serializes writes to
+// guard against concurrent-save corruption.
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type FSStore struct {
+	root string
+
+	mu    sync.Mutex // guards locks
+	locks map[string]*sync.Mutex
+}
+
+// NewFSStore returns a RevisionStore that keeps page revisions under root.
+func NewFSStore(root string) *FSStore {
+	return &FSStore{root: root, locks: make(map[string]*sync.Mutex)}
+}
+
+func (s *FSStore) titleLock(title string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.locks[title]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[title] = l
+	}
+	return l
+}
+
+func (s *FSStore) dir(title string) string {
+	return filepath.Join(s.root, title)
+}
+
+func (s *FSStore) headFile(title string) string {
+	return filepath.Join(s.dir(title), "HEAD")
+}
+
+func (s *FSStore) revisionFile(title, rev string) string {
+	return filepath.Join(s.dir(title), rev+".txt")
+}
+
+// revIDMu guards lastRevID so two Puts landing in the same clock tick
+// still get distinct, increasing ids instead of colliding.
+var (
+	revIDMu   sync.Mutex
+	lastRevID int64
+)
+
+// newRevisionID generates a monotonically increasing, lexicographically
+// sortable revision id. It's seeded from the current time, but falls back
+// to lastRevID+1 when the clock hasn't advanced since the previous call so
+// concurrent or rapid-fire Puts never reuse an id.
+func newRevisionID() string {
+	revIDMu.Lock()
+	defer revIDMu.Unlock()
+	n := time.Now().UnixNano()
+	if n <= lastRevID {
+		n = lastRevID + 1
+	}
+	lastRevID = n
+	return strconv.FormatInt(n, 10)
+}
+
+func (s *FSStore) Get(title string) ([]byte, error) {
+	head, err := ioutil.ReadFile(s.headFile(title))
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRevision(title, strings.TrimSpace(string(head)))
+}
+
+func (s *FSStore) GetRevision(title, rev string) ([]byte, error) {
+	return ioutil.ReadFile(s.revisionFile(title, rev))
+}
+
+func (s *FSStore) Put(title string, body []byte) error {
+	lock := s.titleLock(title)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := os.MkdirAll(s.dir(title), 0700); err != nil {
+		return err
+	}
+	rev := newRevisionID()
+	if err := ioutil.WriteFile(s.revisionFile(title, rev), body, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.headFile(title), []byte(rev), 0600)
+}
+
+func (s *FSStore) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	titles := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			titles = append(titles, e.Name())
+		}
+	}
+	sort.Strings(titles)
+	return titles, nil
+}
+
+func (s *FSStore) ListRevisions(title string) ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir(title))
+	if err != nil {
+		return nil, err
+	}
+	revs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".txt") {
+			continue
+		}
+		revs = append(revs, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(revs)
+	return revs, nil
+}
+
+func (s *FSStore) Delete(title string) error {
+	return os.RemoveAll(s.dir(title))
+}
+
+// GetACL reads a page's .acl sidecar from its revision directory.
+func (s *FSStore) GetACL(title string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.dir(title), ".acl"))
+}
@@ -5,41 +5,71 @@ package main
 
 // Define Imports
 import (
+	"context"
+	"flag"
 	"html/template"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"regexp"
+	"syscall"
 )
 
 // Define Variables
-var templates = template.Must(template.ParseFiles("templates/edit.html", "templates/view.html"))
-var validPath = regexp.MustCompile("^/(edit|save|view)/([a-zA-Z0-9]+)$")
+var templates = template.Must(template.ParseFiles("templates/edit.html", "templates/view.html", "templates/history.html", "templates/diff.html", "templates/search.html", "templates/login.html"))
+var validPath = regexp.MustCompile("^/(edit|save|view|history|diff|restore)/([a-zA-Z0-9]+)$")
 var linkPath = regexp.MustCompile("\\[([a-zA-Z0-9]+)\\]")
 
+// store is the persistence backend selected by the -store flag in main.
+var store RevisionStore
+
+// defaultRenderer is the Markdown pipeline used unless a request asks
+// for ?raw=1, set once store is ready in main.
+var defaultRenderer Renderer
+
+// searchIdx is the full-text search index, set in main and persisted
+// to indexPath on graceful shutdown.
+var searchIdx *SearchIndex
+
+const indexPath = "data/.index.gob"
+
+var (
+	storeFlag = flag.String("store", "fs", "persistence backend to use: fs, mem, or sqlite")
+	dataDir   = flag.String("data", "data", "directory the fs store keeps page revisions under")
+	dbPath    = flag.String("db", "data/wiki.db", "file the sqlite store keeps its database in")
+)
+
 // Define Data Structures
 // Slices are similar to arrays but more flexible and more efficient. Reference: https://go.dev/blog/slices-intro
 type Page struct {
 	Title       string
 	Body        []byte
 	DisplayBody template.HTML
+	Revision    string
+	Revisions   []string
+	CSRFToken   string
 }
 
 // Define Functions
-// Save Method for Persistent Storage
+// Save Method for Persistent Storage: writes a new revision via the store.
 func (p *Page) save() error {
-	filename := p.Title + ".txt"
-	return ioutil.WriteFile("data/"+filename, p.Body, 0600)
+	return store.Put(p.Title, p.Body)
 }
 
-// Loading Pages and Catch Errors
-func loadPage(title string) (*Page, error) {
-	filename := title + ".txt"
-	body, err := ioutil.ReadFile("data/" + filename)
+// Loading Pages and Catch Errors. An empty rev loads the current HEAD revision.
+func loadPage(title, rev string) (*Page, error) {
+	var body []byte
+	var err error
+	if rev == "" {
+		body, err = store.Get(title)
+	} else {
+		body, err = store.GetRevision(title, rev)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &Page{Title: title, Body: body}, nil
+	return &Page{Title: title, Body: body, Revision: rev}, nil
 }
 
 // References the HTML via Templates rather than hardcoding for better readability.
@@ -66,36 +96,72 @@ func viewHandler(w http.ResponseWriter, r *http.Request, title string) {
 	renderTemplate(w, "view", p)
 }*/
 
-// Allows users to view a Wiki Page by handling URL's with '/view/' and Displays Interlinkable Pages
+// Allows users to view a Wiki Page by handling URL's with '/view/' and Displays Interlinkable Pages.
+// An optional ?rev=<id> renders a specific revision instead of the current HEAD.
+// An optional ?raw=1 selects PlainRenderer instead of the Markdown pipeline.
+// Pages stay public by default; a .acl sidecar can restrict reading.
 func viewHandler(w http.ResponseWriter, r *http.Request, title string) {
-	p, err := loadPage(title)
+	username, _ := currentUser(r)
+	if !canRead(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	p, err := loadPage(title, r.URL.Query().Get("rev"))
 	if err != nil {
 		http.Redirect(w, r, "/edit/"+title, http.StatusFound)
 		return
 	}
 
-	escapedBody := []byte(template.HTMLEscapeString(string(p.Body)))
+	renderer := defaultRenderer
+	if r.URL.Query().Get("raw") == "1" {
+		renderer = PlainRenderer{}
+	}
 
-	p.DisplayBody = template.HTML(linkPath.ReplaceAllFunc(escapedBody, func(str []byte) []byte {
-		matched := linkPath.FindStringSubmatch(string(str))
-		out := []byte("<a href=\"/view/" + matched[1] + "\">" + matched[1] + "</a>")
-		return out
-	}))
+	displayBody, err := renderer.Render(p.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.DisplayBody = displayBody
 
 	renderTemplate(w, "view", p)
 }
 
-// Allows users to edit a Wiki Page by handling URL's with '/edit/'
-func editHandler(w http.ResponseWriter, r *http.Request, title string) {
-	p, err := loadPage(title)
+// pageExists reports whether title has any saved revision, used by the
+// Markdown renderer to red-link wiki-link targets that don't exist yet.
+func pageExists(title string) bool {
+	_, err := store.Get(title)
+	return err == nil
+}
+
+// Allows users to edit a Wiki Page by handling URL's with '/edit/'.
+// Requires login; a .acl sidecar can further restrict who may write.
+func editHandler(w http.ResponseWriter, r *http.Request, title, username string) {
+	if !canWrite(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	p, err := loadPage(title, "")
 	if err != nil {
 		p = &Page{Title: title}
 	}
+	p.CSRFToken = csrfToken(username)
 	renderTemplate(w, "edit", p)
 }
 
-// Allows users to save a Wiki Page after using the edit function, and redirecting back to the View Handler with '/view/'
-func saveHandler(w http.ResponseWriter, r *http.Request, title string) {
+// Allows users to save a Wiki Page after using the edit function, and redirecting back to the View Handler with '/view/'.
+// Requires login, a matching CSRF token, and write access under the page's .acl.
+func saveHandler(w http.ResponseWriter, r *http.Request, title, username string) {
+	if !canWrite(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("csrf_token") != csrfToken(username) {
+		http.Error(w, "invalid csrf token", http.StatusForbidden)
+		return
+	}
+
 	body := r.FormValue("body")
 	p := &Page{Title: title, Body: []byte(body)}
 	err := p.save()
@@ -103,6 +169,128 @@ func saveHandler(w http.ResponseWriter, r *http.Request, title string) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	searchIdx.IndexPage(title, p.Body)
+	http.Redirect(w, r, "/view/"+title, http.StatusFound)
+}
+
+// searchResultLimit is how many results searchHandler renders.
+const searchResultLimit = 20
+
+// Tokenizes q, scores every page with BM25 and renders search.html via '/search?q=...'.
+// Results are filtered through canRead so a page's .acl applies to search
+// the same way it applies to /view/<title>.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	username, _ := currentUser(r)
+	q := r.URL.Query().Get("q")
+
+	// Over-fetch, since some hits may be filtered out by canRead below.
+	hits := searchIdx.Search(q, searchResultLimit*5)
+	results := make([]SearchResult, 0, searchResultLimit)
+	for _, hit := range hits {
+		if !canRead(hit.Title, username) {
+			continue
+		}
+		results = append(results, hit)
+		if len(results) == searchResultLimit {
+			break
+		}
+	}
+
+	data := struct {
+		Query   string
+		Results []SearchResult
+	}{
+		Query:   q,
+		Results: results,
+	}
+	if err := templates.ExecuteTemplate(w, "search.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Lists every revision of a page, newest first, via '/history/<title>'.
+func historyHandler(w http.ResponseWriter, r *http.Request, title string) {
+	username, ok := currentUser(r)
+	if !canRead(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	revs, err := store.ListRevisions(title)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	for i, j := 0, len(revs)-1; i < j; i, j = i+1, j-1 {
+		revs[i], revs[j] = revs[j], revs[i]
+	}
+	p := &Page{Title: title, Revisions: revs}
+	if ok {
+		p.CSRFToken = csrfToken(username)
+	}
+	renderTemplate(w, "history", p)
+}
+
+// Renders a unified diff between two revisions via '/diff/<title>?a=<id>&b=<id>'.
+func diffHandler(w http.ResponseWriter, r *http.Request, title string) {
+	username, _ := currentUser(r)
+	if !canRead(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	a := r.URL.Query().Get("a")
+	b := r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		http.Error(w, "diff requires both a and b revision ids", http.StatusBadRequest)
+		return
+	}
+	pa, err := loadPage(title, a)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	pb, err := loadPage(title, b)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	out := unifiedDiff(title+"@"+a, title+"@"+b, pa.Body, pb.Body)
+	p := &Page{Title: title, Body: []byte(out)}
+	renderTemplate(w, "diff", p)
+}
+
+// Writes the chosen revision's body as a new head revision via '/restore/<title>?rev=<id>'.
+// Requires POST, login, write access under the page's .acl, and a matching
+// CSRF token, same as save -- restore mutates content, so a bare GET link
+// would let a third-party page trigger it against a logged-in editor.
+func restoreHandler(w http.ResponseWriter, r *http.Request, title, username string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "restore requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !canWrite(title, username) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("csrf_token") != csrfToken(username) {
+		http.Error(w, "invalid csrf token", http.StatusForbidden)
+		return
+	}
+	rev := r.URL.Query().Get("rev")
+	if rev == "" {
+		http.Error(w, "restore requires a rev parameter", http.StatusBadRequest)
+		return
+	}
+	old, err := loadPage(title, rev)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	p := &Page{Title: title, Body: old.Body}
+	if err := p.save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	searchIdx.IndexPage(title, p.Body)
 	http.Redirect(w, r, "/view/"+title, http.StatusFound)
 }
 
@@ -127,10 +315,64 @@ func makeHandler(fn func(http.ResponseWriter, *http.Request, string)) http.Handl
 
 // Main Event Loop
 func main() {
+	flag.Parse()
+	loadSessionSecret()
+	switch *storeFlag {
+	case "fs":
+		store = NewFSStore(*dataDir)
+	case "mem":
+		store = NewMemStore()
+	case "sqlite":
+		s, err := NewSQLiteStore(*dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = s
+	default:
+		log.Fatalf("unknown -store %q: want fs, mem, or sqlite", *storeFlag)
+	}
+	defaultRenderer = NewMarkdownRenderer(pageExists)
+
+	idx, err := LoadSearchIndex(indexPath, store.Get)
+	if err != nil {
+		log.Printf("search index: %v; rebuilding from store", err)
+		idx, err = RebuildSearchIndex(store)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	searchIdx = idx
+
+	if u, err := loadUsers(usersPath); err != nil {
+		log.Printf("users: %v; logins will fail until %s exists", err, usersPath)
+	} else {
+		users = u
+	}
+
 	http.HandleFunc("/", indexHandler)
 	http.HandleFunc("/view/", makeHandler(viewHandler))
-	http.HandleFunc("/edit/", makeHandler(editHandler))
-	http.HandleFunc("/save/", makeHandler(saveHandler))
+	http.HandleFunc("/edit/", makeAuthHandler(editHandler))
+	http.HandleFunc("/save/", makeAuthHandler(saveHandler))
+	http.HandleFunc("/history/", makeHandler(historyHandler))
+	http.HandleFunc("/diff/", makeHandler(diffHandler))
+	http.HandleFunc("/restore/", makeAuthHandler(restoreHandler))
+	http.HandleFunc("/search", searchHandler)
+	http.HandleFunc("/login", loginHandler)
+	http.HandleFunc("/logout", logoutHandler)
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
-	log.Fatal(http.ListenAndServe(":8080", nil))
+
+	srv := &http.Server{Addr: ":8080"}
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+		if err := searchIdx.SaveGob(indexPath); err != nil {
+			log.Printf("search index save failed: %v", err)
+		}
+		srv.Shutdown(context.Background())
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
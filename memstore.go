@@ -0,0 +1,97 @@
+package main
+
+// MemStore is an in-memory RevisionStore, used in tests so they don't
+// need a data/ temp dir.
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+type memRevision struct {
+	id   string
+	body []byte
+}
+
+type MemStore struct {
+	mu    sync.RWMutex
+	pages map[string][]memRevision
+}
+
+// NewMemStore returns an empty in-memory RevisionStore.
+func NewMemStore() *MemStore {
+	return &MemStore{pages: make(map[string][]memRevision)}
+}
+
+func (s *MemStore) Get(title string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revs := s.pages[title]
+	if len(revs) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return revs[len(revs)-1].body, nil
+}
+
+func (s *MemStore) GetRevision(title, rev string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.pages[title] {
+		if r.id == rev {
+			return r.body, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (s *MemStore) Put(title string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rev := newRevisionID()
+	cp := append([]byte(nil), body...)
+	s.pages[title] = append(s.pages[title], memRevision{id: rev, body: cp})
+	return nil
+}
+
+func (s *MemStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	titles := make([]string, 0, len(s.pages))
+	for t := range s.pages {
+		titles = append(titles, t)
+	}
+	sort.Strings(titles)
+	return titles, nil
+}
+
+func (s *MemStore) ListRevisions(title string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revs, ok := s.pages[title]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	ids := make([]string, len(revs))
+	for i, r := range revs {
+		ids[i] = r.id
+	}
+	return ids, nil
+}
+
+func (s *MemStore) Delete(title string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pages[title]; !ok {
+		return os.ErrNotExist
+	}
+	delete(s.pages, title)
+	return nil
+}
+
+// GetACL always reports not-exist: MemStore keeps no sidecar data, so
+// every page it serves is world-readable/authenticated-writable, same
+// as an FSStore page missing a .acl.
+func (s *MemStore) GetACL(title string) ([]byte, error) {
+	return nil, os.ErrNotExist
+}
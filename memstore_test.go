@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestMemStoreGetPutRoundTrip(t *testing.T) {
+	s := NewMemStore()
+	if err := s.Put("Home", []byte("hello")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := s.Get("Home")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("Get returned %q, want %q", got, "hello")
+	}
+}
+
+func TestMemStoreGetMissing(t *testing.T) {
+	s := NewMemStore()
+	if _, err := s.Get("Nope"); err == nil {
+		t.Fatal("Get of a missing title: want error, got nil")
+	}
+}
+
+func TestMemStoreRevisions(t *testing.T) {
+	s := NewMemStore()
+	if err := s.Put("Home", []byte("v1")); err != nil {
+		t.Fatalf("Put v1: %v", err)
+	}
+	if err := s.Put("Home", []byte("v2")); err != nil {
+		t.Fatalf("Put v2: %v", err)
+	}
+
+	revs, err := s.ListRevisions("Home")
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("ListRevisions returned %d revisions, want 2", len(revs))
+	}
+
+	first, err := s.GetRevision("Home", revs[0])
+	if err != nil {
+		t.Fatalf("GetRevision(%s): %v", revs[0], err)
+	}
+	if string(first) != "v1" {
+		t.Fatalf("GetRevision(%s) = %q, want %q", revs[0], first, "v1")
+	}
+
+	head, err := s.Get("Home")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(head) != "v2" {
+		t.Fatalf("Get (HEAD) = %q, want %q", head, "v2")
+	}
+}
+
+func TestMemStoreListAndDelete(t *testing.T) {
+	s := NewMemStore()
+	s.Put("Alpha", []byte("a"))
+	s.Put("Beta", []byte("b"))
+
+	titles, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(titles) != 2 || titles[0] != "Alpha" || titles[1] != "Beta" {
+		t.Fatalf("List returned %v, want sorted [Alpha Beta]", titles)
+	}
+
+	if err := s.Delete("Alpha"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get("Alpha"); err == nil {
+		t.Fatal("Get after Delete: want error, got nil")
+	}
+	if err := s.Delete("Alpha"); err == nil {
+		t.Fatal("Delete of an already-deleted title: want error, got nil")
+	}
+}
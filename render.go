@@ -0,0 +1,187 @@
+package main
+
+// Markdown rendering pipeline: CommonMark via goldmark, with internal
+// [Name] / [[Name|Label]] wiki-link syntax resolved to /view/ links by
+// an AST transformer that rewrites ast.Text nodes after parsing (so code
+// spans and code blocks are left untouched), then styled class="new"
+// after rendering if their target page doesn't exist yet (a red-link).
+// XSS safety is preserved by running a sanitizer over the final
+// rendered HTML rather than escaping the raw Markdown source.
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	gtext "github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Renderer turns a page body into safe, displayable HTML. Selectable
+// per-request: viewHandler uses MarkdownRenderer by default and falls
+// back to PlainRenderer when the request carries ?raw=1.
+type Renderer interface {
+	Render(body []byte) (template.HTML, error)
+}
+
+var (
+	wikiLinkToken    = regexp.MustCompile(`\[\[([a-zA-Z0-9]+)\|([^\]]+)\]\]|\[([a-zA-Z0-9]+)\]`)
+	wikiLinkRendered = regexp.MustCompile(`<a href="/view/([a-zA-Z0-9]+)" title="wikilink">`)
+)
+
+var sanitizer = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").OnElements("a")
+	return p
+}()
+
+// MarkdownRenderer renders CommonMark Markdown, resolving [Name] and
+// [[Name|Label]] tokens to /view/ links and red-linking targets for
+// which exists returns false.
+type MarkdownRenderer struct {
+	md     goldmark.Markdown
+	exists func(title string) bool
+}
+
+// NewMarkdownRenderer returns a MarkdownRenderer that consults exists
+// to decide whether a wiki-link target is a red-link.
+func NewMarkdownRenderer(exists func(title string) bool) *MarkdownRenderer {
+	md := goldmark.New(goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(wikiLinkTransformer{}, 500)),
+	))
+	return &MarkdownRenderer{md: md, exists: exists}
+}
+
+func (mr *MarkdownRenderer) Render(body []byte) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := mr.md.Convert(body, &buf); err != nil {
+		return "", err
+	}
+
+	withRedLinks := wikiLinkRendered.ReplaceAllFunc(buf.Bytes(), func(match []byte) []byte {
+		title := wikiLinkRendered.FindSubmatch(match)[1]
+		href := []byte(`<a href="/view/` + string(title) + `"`)
+		if mr.exists == nil || !mr.exists(string(title)) {
+			href = append(href, []byte(` class="new"`)...)
+		}
+		return append(href, '>')
+	})
+
+	return template.HTML(sanitizer.SanitizeBytes(withRedLinks)), nil
+}
+
+// wikiLinkTransformer resolves [Name] and [[Name|Label]] tokens to
+// /view/ links by rewriting ast.Text nodes once the document is fully
+// parsed, so it never sees inside a CodeSpan/FencedCodeBlock/CodeBlock
+// and can't corrupt bracketed text a page uses in code (array indices,
+// footnote refs, etc.).
+type wikiLinkTransformer struct{}
+
+func (wikiLinkTransformer) Transform(doc *gast.Document, reader gtext.Reader, pc parser.Context) {
+	rewriteWikiLinkChildren(doc, reader.Source())
+}
+
+// rewriteWikiLinkChildren walks parent's children, rewriting runs of
+// plain Text siblings in place and recursing into everything else
+// except code, whose contents must render verbatim. Adjacent Text
+// nodes are merged before matching: goldmark's own link-reference
+// parser splits a run like "See [Home] end." into three sibling Text
+// nodes ("See [", "Home", "] end.") while failing to resolve it as a
+// real link, so a wiki-link token can straddle more than one node.
+func rewriteWikiLinkChildren(parent gast.Node, source []byte) {
+	var next gast.Node
+	for c := parent.FirstChild(); c != nil; c = next {
+		switch {
+		case c.Kind() == gast.KindCodeSpan || c.Kind() == gast.KindFencedCodeBlock || c.Kind() == gast.KindCodeBlock:
+			next = c.NextSibling()
+		case c.Kind() == gast.KindText && !c.(*gast.Text).IsRaw():
+			next = rewriteWikiLinkRun(parent, c.(*gast.Text), source)
+		default:
+			next = c.NextSibling()
+			rewriteWikiLinkChildren(c, source)
+		}
+	}
+}
+
+// rewriteWikiLinkRun merges the run of plain-text siblings starting at
+// first, rewrites any wiki-link tokens found across the whole run into
+// a String/Link sequence, and returns the sibling the walk should
+// resume from (the node right after the run, possibly nil).
+func rewriteWikiLinkRun(parent gast.Node, first *gast.Text, source []byte) gast.Node {
+	var raw []byte
+	last := gast.Node(first)
+	for n := gast.Node(first); n != nil; n = n.NextSibling() {
+		t, ok := n.(*gast.Text)
+		if !ok || t.IsRaw() {
+			break
+		}
+		raw = append(raw, t.Text(source)...)
+		last = n
+	}
+	after := last.NextSibling()
+
+	matches := wikiLinkToken.FindAllSubmatchIndex(raw, -1)
+	if matches == nil {
+		return after
+	}
+
+	for n := gast.Node(first); n != nil; {
+		nxt := n.NextSibling()
+		parent.RemoveChild(parent, n)
+		if n == last {
+			break
+		}
+		n = nxt
+	}
+
+	insert := func(node gast.Node) {
+		if after != nil {
+			parent.InsertBefore(parent, after, node)
+		} else {
+			parent.AppendChild(parent, node)
+		}
+	}
+
+	pos := 0
+	for _, m := range matches {
+		if m[0] > pos {
+			insert(gast.NewString(raw[pos:m[0]]))
+		}
+
+		var target, label []byte
+		if m[2] != -1 {
+			target, label = raw[m[2]:m[3]], raw[m[4]:m[5]]
+		} else {
+			target, label = raw[m[6]:m[7]], raw[m[6]:m[7]]
+		}
+
+		link := gast.NewLink()
+		link.Destination = []byte("/view/" + string(target))
+		link.Title = []byte("wikilink")
+		link.AppendChild(link, gast.NewString(label))
+		insert(link)
+
+		pos = m[1]
+	}
+	if pos < len(raw) {
+		insert(gast.NewString(raw[pos:]))
+	}
+	return after
+}
+
+// PlainRenderer reproduces the pipeline's pre-Markdown behavior: escape
+// the raw body and linkify bare [Name] tokens. Selected via ?raw=1.
+type PlainRenderer struct{}
+
+func (PlainRenderer) Render(body []byte) (template.HTML, error) {
+	escaped := []byte(template.HTMLEscapeString(string(body)))
+	linked := linkPath.ReplaceAllFunc(escaped, func(str []byte) []byte {
+		matched := linkPath.FindStringSubmatch(string(str))
+		return []byte(`<a href="/view/` + matched[1] + `">` + matched[1] + `</a>`)
+	})
+	return template.HTML(linked), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestMarkdownRendererWikiLinks(t *testing.T) {
+	exists := func(title string) bool { return title == "Home" }
+	mr := NewMarkdownRenderer(exists)
+
+	out, err := mr.Render([]byte("See [Home] and [[Home|the home page]] and [Missing]."))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		`<a href="/view/Home" rel="nofollow">Home</a>`,
+		`<a href="/view/Home" rel="nofollow">the home page</a>`,
+		`<a href="/view/Missing" class="new" rel="nofollow">Missing</a>`,
+	} {
+		if !contains(got, want) {
+			t.Errorf("Render output missing %q\ngot: %s", want, got)
+		}
+	}
+}
+
+func TestMarkdownRendererLeavesCodeAlone(t *testing.T) {
+	mr := NewMarkdownRenderer(func(string) bool { return true })
+
+	out, err := mr.Render([]byte("```\narr[0] = 1\n```\n\nInline `arr[0]` code too."))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	got := string(out)
+
+	if !contains(got, "arr[0] = 1") {
+		t.Errorf("fenced code block was mangled, got: %s", got)
+	}
+	if !contains(got, "arr[0]") || contains(got, `href="/view/0"`) {
+		t.Errorf("inline code span was mangled into a wiki-link, got: %s", got)
+	}
+}
+
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) && indexOf(s, substr) >= 0
+}
+
+func indexOf(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
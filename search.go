@@ -0,0 +1,334 @@
+package main
+
+// Full-text search: an inverted index over every page, scored with
+// BM25 (k1=1.2, b=0.75). The index is rebuilt from the store on
+// startup (or loaded from data/.index.gob if present) and updated
+// incrementally whenever saveHandler writes a page.
+
+import (
+	"encoding/gob"
+	"html/template"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// posting is one (document, term-frequency) entry in a term's postings list.
+type posting struct {
+	DocID int
+	TF    int
+}
+
+// docInfo is the per-document metadata needed to score it.
+type docInfo struct {
+	Title  string
+	Length int
+}
+
+// SearchIndex is an inverted index over page bodies. Reads take an
+// RLock so concurrent searches don't block each other; only
+// IndexPage/RemovePage take the write lock.
+type SearchIndex struct {
+	mu        sync.RWMutex
+	postings  map[string][]posting
+	docs      map[int]*docInfo
+	titleToID map[string]int
+	nextID    int
+	totalLen  int
+
+	fetch func(title string) ([]byte, error)
+}
+
+// NewSearchIndex returns an empty index that uses fetch (typically
+// store.Get) to load a document's body when building search snippets.
+func NewSearchIndex(fetch func(title string) ([]byte, error)) *SearchIndex {
+	return &SearchIndex{
+		postings:  make(map[string][]posting),
+		docs:      make(map[int]*docInfo),
+		titleToID: make(map[string]int),
+		fetch:     fetch,
+	}
+}
+
+// RebuildSearchIndex walks every page in store and indexes it from scratch.
+func RebuildSearchIndex(store Store) (*SearchIndex, error) {
+	titles, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	idx := NewSearchIndex(store.Get)
+	for _, title := range titles {
+		body, err := store.Get(title)
+		if err != nil {
+			continue
+		}
+		idx.IndexPage(title, body)
+	}
+	return idx, nil
+}
+
+// IndexPage (re)indexes a page, first removing any terms from its
+// previous body so saveHandler can call this on every edit.
+func (idx *SearchIndex) IndexPage(title string, body []byte) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if id, ok := idx.titleToID[title]; ok {
+		idx.removeDocLocked(id)
+	}
+
+	terms := tokenize(string(body))
+	tf := make(map[string]int, len(terms))
+	for _, t := range terms {
+		tf[t]++
+	}
+
+	id := idx.nextID
+	idx.nextID++
+	idx.docs[id] = &docInfo{Title: title, Length: len(terms)}
+	idx.titleToID[title] = id
+	idx.totalLen += len(terms)
+	for term, freq := range tf {
+		idx.postings[term] = append(idx.postings[term], posting{DocID: id, TF: freq})
+	}
+}
+
+// RemovePage removes a page's terms from the index, e.g. after a delete.
+func (idx *SearchIndex) RemovePage(title string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if id, ok := idx.titleToID[title]; ok {
+		idx.removeDocLocked(id)
+	}
+}
+
+func (idx *SearchIndex) removeDocLocked(id int) {
+	info, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	idx.totalLen -= info.Length
+	delete(idx.docs, id)
+	delete(idx.titleToID, info.Title)
+	for term, list := range idx.postings {
+		kept := list[:0]
+		for _, p := range list {
+			if p.DocID != id {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.postings, term)
+		} else {
+			idx.postings[term] = kept
+		}
+	}
+}
+
+// SearchResult is one ranked hit rendered by search.html.
+type SearchResult struct {
+	Title   string
+	Score   float64
+	Snippet template.HTML
+}
+
+// Search tokenizes query, scores every matching document with BM25 and
+// returns up to limit results ordered best-first.
+func (idx *SearchIndex) Search(query string, limit int) []SearchResult {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	n := len(idx.docs)
+	if n == 0 {
+		idx.mu.RUnlock()
+		return nil
+	}
+	avgdl := float64(idx.totalLen) / float64(n)
+	scores := make(map[int]float64)
+	for _, t := range terms {
+		list := idx.postings[t]
+		df := len(list)
+		if df == 0 {
+			continue
+		}
+		idf := math.Log((float64(n-df)+0.5)/(float64(df)+0.5) + 1)
+		for _, p := range list {
+			doc := idx.docs[p.DocID]
+			tf := float64(p.TF)
+			denom := tf + bm25K1*(1-bm25B+bm25B*float64(doc.Length)/avgdl)
+			scores[p.DocID] += idf * (tf * (bm25K1 + 1)) / denom
+		}
+	}
+
+	type ranked struct {
+		title string
+		score float64
+	}
+	results := make([]ranked, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, ranked{title: idx.docs[id].Title, score: score})
+	}
+	fetch := idx.fetch
+	idx.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	out := make([]SearchResult, len(results))
+	for i, r := range results {
+		var body []byte
+		if fetch != nil {
+			body, _ = fetch(r.title)
+		}
+		out[i] = SearchResult{Title: r.title, Score: r.score, Snippet: snippet(body, terms)}
+	}
+	return out
+}
+
+// indexSnapshot is the gob-serializable form of a SearchIndex.
+type indexSnapshot struct {
+	Postings  map[string][]posting
+	Docs      map[int]*docInfo
+	TitleToID map[string]int
+	NextID    int
+	TotalLen  int
+}
+
+// SaveGob persists the index to path, used on graceful shutdown.
+func (idx *SearchIndex) SaveGob(path string) error {
+	idx.mu.RLock()
+	snap := indexSnapshot{
+		Postings:  idx.postings,
+		Docs:      idx.docs,
+		TitleToID: idx.titleToID,
+		NextID:    idx.nextID,
+		TotalLen:  idx.totalLen,
+	}
+	idx.mu.RUnlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(snap)
+}
+
+// LoadSearchIndex loads an index previously written by SaveGob.
+func LoadSearchIndex(path string, fetch func(title string) ([]byte, error)) (*SearchIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snap indexSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &SearchIndex{
+		postings:  snap.Postings,
+		docs:      snap.Docs,
+		titleToID: snap.TitleToID,
+		nextID:    snap.NextID,
+		totalLen:  snap.TotalLen,
+		fetch:     fetch,
+	}, nil
+}
+
+var tokenRe = regexp.MustCompile(`[a-z0-9]+`)
+
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true, "their": true,
+	"then": true, "there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "will": true, "with": true,
+}
+
+// tokenize lowercases s, strips punctuation and drops stopwords.
+func tokenize(s string) []string {
+	words := tokenRe.FindAllString(strings.ToLower(s), -1)
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		if !stopwords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+const snippetRadius = 40
+
+// snippet returns an HTML-escaped window of ~80 chars around the first
+// occurrence of any query term in body, with hits wrapped in <mark>.
+func snippet(body []byte, terms []string) template.HTML {
+	text := string(body)
+	lower := strings.ToLower(text)
+
+	pos := -1
+	var hit string
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i != -1 && (pos == -1 || i < pos) {
+			pos = i
+			hit = t
+		}
+	}
+
+	start, end := 0, len(text)
+	switch {
+	case pos != -1:
+		start = pos - snippetRadius
+		if start < 0 {
+			start = 0
+		}
+		end = pos + len(hit) + snippetRadius
+		if end > len(text) {
+			end = len(text)
+		}
+	case end > 2*snippetRadius:
+		end = 2 * snippetRadius
+	}
+
+	start, end = backToRuneBoundary(text, start), forwardToRuneBoundary(text, end)
+	escaped := template.HTMLEscapeString(text[start:end])
+	if hit != "" {
+		re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(hit))
+		escaped = re.ReplaceAllString(escaped, "<mark>$0</mark>")
+	}
+	return template.HTML(escaped)
+}
+
+// backToRuneBoundary moves i left until it lands on a UTF-8 rune
+// boundary in s, so a snippet window computed from byte offsets never
+// slices a multi-byte character in half.
+func backToRuneBoundary(s string, i int) int {
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// forwardToRuneBoundary moves i right until it lands on a UTF-8 rune
+// boundary in s (or reaches len(s)).
+func forwardToRuneBoundary(s string, i int) int {
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
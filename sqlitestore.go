@@ -0,0 +1,157 @@
+package main
+
+// SQLiteStore persists pages and their revisions in a SQLite database,
+// for deployments that want one portable file instead of a data/ tree.
+
+import (
+	"database/sql"
+	"os"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) a SQLite database at path
+// and ensures its schema exists.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pages (
+			title TEXT PRIMARY KEY,
+			head  INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS revisions (
+			title TEXT NOT NULL,
+			rev   INTEGER NOT NULL,
+			body  BLOB NOT NULL,
+			PRIMARY KEY (title, rev)
+		);
+		CREATE TABLE IF NOT EXISTS acls (
+			title TEXT PRIMARY KEY,
+			acl   TEXT NOT NULL
+		);
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Get(title string) ([]byte, error) {
+	var head int64
+	if err := s.db.QueryRow(`SELECT head FROM pages WHERE title = ?`, title).Scan(&head); err != nil {
+		return nil, err
+	}
+	return s.GetRevision(title, strconv.FormatInt(head, 10))
+}
+
+func (s *SQLiteStore) GetRevision(title, rev string) ([]byte, error) {
+	n, err := strconv.ParseInt(rev, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if err := s.db.QueryRow(`SELECT body FROM revisions WHERE title = ? AND rev = ?`, title, n).Scan(&body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *SQLiteStore) Put(title string, body []byte) error {
+	rev, err := strconv.ParseInt(newRevisionID(), 10, 64)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO revisions (title, rev, body) VALUES (?, ?, ?)`, title, rev, body); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO pages (title, head) VALUES (?, ?)
+		ON CONFLICT(title) DO UPDATE SET head = excluded.head
+	`, title, rev); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) List() ([]string, error) {
+	rows, err := s.db.Query(`SELECT title FROM pages ORDER BY title`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		titles = append(titles, t)
+	}
+	return titles, rows.Err()
+}
+
+func (s *SQLiteStore) ListRevisions(title string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT rev FROM revisions WHERE title = ? ORDER BY rev`, title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []string
+	for rows.Next() {
+		var n int64
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		revs = append(revs, strconv.FormatInt(n, 10))
+	}
+	return revs, rows.Err()
+}
+
+func (s *SQLiteStore) Delete(title string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM revisions WHERE title = ?`, title); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM pages WHERE title = ?`, title); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetACL reads a page's ACL from the acls table, the SQLiteStore
+// equivalent of FSStore's .acl sidecar file. It reports os.ErrNotExist
+// when the page has no row, same as a missing sidecar, so canRead and
+// canWrite apply the same default-open semantics across backends. An
+// operator populates the table directly (e.g. via the sqlite3 CLI);
+// there is no HTTP-facing way to set an ACL under any backend.
+func (s *SQLiteStore) GetACL(title string) ([]byte, error) {
+	var acl []byte
+	if err := s.db.QueryRow(`SELECT acl FROM acls WHERE title = ?`, title).Scan(&acl); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, os.ErrNotExist
+		}
+		return nil, err
+	}
+	return acl, nil
+}
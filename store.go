@@ -0,0 +1,22 @@
+package main
+
+// Store abstracts page persistence so loadPage/save and the handlers
+// don't call ioutil directly. Get/Put operate on a page's current head
+// revision; List enumerates known page titles; Delete removes a page
+// and all of its revisions; GetACL returns a page's raw .acl sidecar
+// bytes, or a not-exist error if the backend keeps no ACL for it.
+type Store interface {
+	Get(title string) ([]byte, error)
+	Put(title string, body []byte) error
+	List() ([]string, error)
+	Delete(title string) error
+	GetACL(title string) ([]byte, error)
+}
+
+// RevisionStore is implemented by stores that keep page history and can
+// serve a specific past revision or list every revision id for a page.
+type RevisionStore interface {
+	Store
+	GetRevision(title, rev string) ([]byte, error)
+	ListRevisions(title string) ([]string, error)
+}